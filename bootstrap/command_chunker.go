@@ -0,0 +1,135 @@
+package bootstrap
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+)
+
+// Conservative per-platform argv length limits, used to decide when a
+// prepared command needs to be split into multiple sub-invocations.
+const (
+	ArgMaxLinux   = 131072
+	ArgMaxDarwin  = 262144
+	ArgMaxWindows = 32767
+)
+
+// CurrentArgMax returns the argv length limit for the platform this process
+// is running on.
+func CurrentArgMax() int {
+	switch runtime.GOOS {
+	case "windows":
+		return ArgMaxWindows
+	case "darwin":
+		return ArgMaxDarwin
+	default:
+		return ArgMaxLinux
+	}
+}
+
+// FileListPlaceholder is the token in a command template that expands to a
+// space-separated list of files, e.g. changed files or artifact paths.
+const FileListPlaceholder = "{{files}}"
+
+// ChunkError reports that preparing a command failed while building the
+// invocation at Index.
+type ChunkError struct {
+	Index int
+	Err   error
+}
+
+func (e *ChunkError) Error() string {
+	return fmt.Sprintf("chunk %d: %v", e.Index, e.Err)
+}
+
+func (e *ChunkError) Unwrap() error {
+	return e.Err
+}
+
+// CommandChunker splits a command template containing FileListPlaceholder
+// into one or more invocations that each fit within a platform's argv
+// length limit, by partitioning the file list across invocations while
+// keeping the template's non-file prefix/suffix intact in every one.
+//
+// This is for hook/plugin commands built by substituting a changed-file or
+// artifact-path list into a template: a linter or formatter invoked over a
+// large changeset can easily produce a command line longer than the OS will
+// accept, and the fix is to run the same command several times over
+// partitions of the file list rather than fail outright.
+type CommandChunker struct {
+	// ArgMax is the argv length limit to split against. Defaults to
+	// CurrentArgMax() when zero.
+	ArgMax int
+
+	// ForceSingleInvocation disables chunking, always returning exactly one
+	// command regardless of its length, for callers that want to opt out.
+	ForceSingleInvocation bool
+}
+
+// Prepare expands template against files, returning one command per
+// sub-invocation needed to stay within ArgMax. If template doesn't contain
+// FileListPlaceholder, files is ignored and a single command is returned.
+func (c *CommandChunker) Prepare(template string, files []string) ([]string, error) {
+	prefix, suffix, hasPlaceholder := splitTemplate(template)
+
+	if !hasPlaceholder || c.ForceSingleInvocation {
+		return []string{expandFileList(prefix, suffix, files)}, nil
+	}
+
+	argMax := c.ArgMax
+	if argMax == 0 {
+		argMax = CurrentArgMax()
+	}
+
+	full := expandFileList(prefix, suffix, files)
+	if len(full) <= argMax {
+		return []string{full}, nil
+	}
+
+	overhead := len(prefix) + len(suffix)
+	if overhead >= argMax {
+		return nil, &ChunkError{Index: 0, Err: fmt.Errorf("command prefix/suffix alone (%d bytes) exceeds ARG_MAX (%d bytes)", overhead, argMax)}
+	}
+
+	var commands []string
+	var chunk []string
+	chunkLen := overhead
+
+	flush := func() {
+		if len(chunk) == 0 {
+			return
+		}
+		commands = append(commands, expandFileList(prefix, suffix, chunk))
+		chunk = nil
+		chunkLen = overhead
+	}
+
+	for _, f := range files {
+		// +1 accounts for the space separating this file from the next
+		added := len(f) + 1
+		if len(chunk) > 0 && chunkLen+added > argMax {
+			flush()
+		}
+		if len(f)+1+overhead > argMax {
+			return nil, &ChunkError{Index: len(commands), Err: fmt.Errorf("single file path %q is too long to fit within ARG_MAX (%d bytes) alongside the command template", f, argMax)}
+		}
+		chunk = append(chunk, f)
+		chunkLen += added
+	}
+	flush()
+
+	return commands, nil
+}
+
+// splitTemplate divides template on its first FileListPlaceholder.
+func splitTemplate(template string) (prefix, suffix string, hasPlaceholder bool) {
+	idx := strings.Index(template, FileListPlaceholder)
+	if idx == -1 {
+		return template, "", false
+	}
+	return template[:idx], template[idx+len(FileListPlaceholder):], true
+}
+
+func expandFileList(prefix, suffix string, files []string) string {
+	return prefix + strings.Join(files, " ") + suffix
+}
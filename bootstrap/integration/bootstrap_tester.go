@@ -13,6 +13,7 @@ import (
 	"sync"
 	"testing"
 
+	"github.com/buildkite/agent/bootstrap"
 	"github.com/buildkite/bintest"
 )
 
@@ -122,6 +123,36 @@ func NewBootstrapTester() (*BootstrapTester, error) {
 	return bt, nil
 }
 
+// WithRepoBackend replaces the tester's git repository fixture with one
+// driven by backend instead of the platform default. It must be called
+// before any hooks or commits are recorded against b.Repo.
+func (b *BootstrapTester) WithRepoBackend(backend RepoBackend) error {
+	if b.Repo != nil {
+		if err := b.Repo.Close(); err != nil {
+			return err
+		}
+	}
+
+	dir, err := ioutil.TempDir("", "bootstrap-git")
+	if err != nil {
+		return err
+	}
+
+	repo, err := newTestGitRepository(dir, backend)
+	if err != nil {
+		return err
+	}
+	b.Repo = repo
+
+	for i, e := range b.Env {
+		if strings.HasPrefix(e, "BUILDKITE_REPO=") {
+			b.Env[i] = "BUILDKITE_REPO=" + repo.Path
+		}
+	}
+
+	return nil
+}
+
 // Mock creates a mock for a binary using bintest
 func (b *BootstrapTester) Mock(name string) (*bintest.Mock, error) {
 	mock, err := bintest.NewMock(filepath.Join(b.PathDir, name))
@@ -206,6 +237,39 @@ func (b *BootstrapTester) ExpectGlobalHook(name string) *bintest.Expectation {
 	return b.hookMock.Expect("global", name)
 }
 
+// ExpectChunkedCommand sets up mock expectations for a command that's
+// expected to be split into exactly expectedChunks sub-invocations when run
+// against totalFiles changed files, because the fully expanded command line
+// would otherwise exceed the platform's ARG_MAX. It panics if totalFiles
+// wouldn't actually produce expectedChunks chunks on this platform, so a
+// wrong expectation fails fast instead of hanging on an unmet mock.
+func (b *BootstrapTester) ExpectChunkedCommand(name string, totalFiles int, expectedChunks int) *bintest.Mock {
+	files := make([]string, totalFiles)
+	for i := range files {
+		files[i] = fmt.Sprintf("file%d.go", i)
+	}
+
+	chunker := &bootstrap.CommandChunker{}
+	commands, err := chunker.Prepare(name+" "+bootstrap.FileListPlaceholder, files)
+	if err != nil {
+		panic(err)
+	}
+	if len(commands) != expectedChunks {
+		panic(fmt.Sprintf("ExpectChunkedCommand(%q, %d, %d): would actually produce %d chunks on this platform", name, totalFiles, expectedChunks, len(commands)))
+	}
+
+	mock, err := b.Mock(name)
+	if err != nil {
+		panic(err)
+	}
+
+	for i := 0; i < expectedChunks; i++ {
+		mock.Expect().AndExitWith(0)
+	}
+
+	return mock
+}
+
 // Run the bootstrap and return any errors
 func (b *BootstrapTester) Run(t *testing.T, env ...string) error {
 	// Mock out the meta-data calls to the agent after checkout
@@ -0,0 +1,59 @@
+package integration
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/buildkite/agent/bootstrap"
+)
+
+// expectedEslintChunks mirrors ExpectChunkedCommand's own chunking logic, so
+// the test stays correct as CurrentArgMax varies by platform instead of
+// hardcoding a count measured on just one of them.
+func expectedEslintChunks(t *testing.T, totalFiles int) int {
+	t.Helper()
+
+	files := make([]string, totalFiles)
+	for i := range files {
+		files[i] = fmt.Sprintf("file%d.go", i)
+	}
+
+	chunker := &bootstrap.CommandChunker{}
+	commands, err := chunker.Prepare("eslint "+bootstrap.FileListPlaceholder, files)
+	if err != nil {
+		t.Fatalf("CommandChunker.Prepare: %v", err)
+	}
+	return len(commands)
+}
+
+func TestExpectChunkedCommand(t *testing.T) {
+	bt, err := NewBootstrapTester()
+	if err != nil {
+		t.Fatalf("NewBootstrapTester: %v", err)
+	}
+	defer bt.Close()
+
+	mock := bt.ExpectChunkedCommand("eslint", 20000, expectedEslintChunks(t, 20000))
+	if mock == nil {
+		t.Fatal("ExpectChunkedCommand returned a nil mock")
+	}
+	if !bt.HasMock("eslint") {
+		t.Error("expected a mock named \"eslint\" to be registered")
+	}
+}
+
+func TestExpectChunkedCommandPanicsOnWrongChunkCount(t *testing.T) {
+	bt, err := NewBootstrapTester()
+	if err != nil {
+		t.Fatalf("NewBootstrapTester: %v", err)
+	}
+	defer bt.Close()
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected ExpectChunkedCommand to panic on a chunk count that doesn't match reality")
+		}
+	}()
+
+	bt.ExpectChunkedCommand("eslint", 20000, expectedEslintChunks(t, 20000)+1)
+}
@@ -0,0 +1,83 @@
+package integration
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// gitRepository is the git repository fixture used by BootstrapTester. All
+// git operations are delegated to a RepoBackend, so the fixture doesn't
+// have to shell out to a system git binary - see WithRepoBackend.
+type gitRepository struct {
+	Path string
+
+	backend RepoBackend
+}
+
+// createTestGitRespository creates a fresh git repository fixture in a new
+// temp dir, using the platform's default RepoBackend, with a single initial
+// commit so that BUILDKITE_COMMIT=HEAD resolves to something.
+func createTestGitRespository() (*gitRepository, error) {
+	dir, err := ioutil.TempDir("", "bootstrap-git")
+	if err != nil {
+		return nil, err
+	}
+
+	return newTestGitRepository(dir, newDefaultRepoBackend(dir))
+}
+
+// newTestGitRepository creates a git repository fixture in dir, using
+// backend for all git operations.
+func newTestGitRepository(dir string, backend RepoBackend) (*gitRepository, error) {
+	if err := backend.Init(dir); err != nil {
+		return nil, err
+	}
+
+	repo := &gitRepository{
+		Path:    dir,
+		backend: backend,
+	}
+
+	testFile := filepath.Join(dir, "test.txt")
+	if err := ioutil.WriteFile(testFile, []byte("This is a test"), 0600); err != nil {
+		return nil, err
+	}
+	if err := repo.Add(testFile); err != nil {
+		return nil, err
+	}
+	if err := repo.Commit("Initial commit"); err != nil {
+		return nil, err
+	}
+
+	return repo, nil
+}
+
+func (r *gitRepository) Add(path string) error {
+	return r.backend.Add(path)
+}
+
+func (r *gitRepository) Commit(format string, args ...interface{}) error {
+	return r.backend.Commit(format, args...)
+}
+
+func (r *gitRepository) CreateBranch(name string) error {
+	return r.backend.CreateBranch(name)
+}
+
+func (r *gitRepository) Tag(name string) error {
+	return r.backend.Tag(name)
+}
+
+func (r *gitRepository) SetRemote(url string) error {
+	return r.backend.SetRemote(url)
+}
+
+func (r *gitRepository) AddSubmodule(path, url string) error {
+	return r.backend.AddSubmodule(path, url)
+}
+
+// Close removes the repository's temp dir.
+func (r *gitRepository) Close() error {
+	return os.RemoveAll(r.Path)
+}
@@ -0,0 +1,63 @@
+package integration
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+)
+
+// shellRepoBackend is a RepoBackend that shells out to a system git binary.
+// It's the default everywhere a system git is reliably present.
+type shellRepoBackend struct {
+	dir string
+}
+
+func newShellRepoBackend(dir string) RepoBackend {
+	return &shellRepoBackend{dir: dir}
+}
+
+func (b *shellRepoBackend) git(args ...string) error {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = b.dir
+	cmd.Env = append(os.Environ(),
+		"GIT_AUTHOR_NAME=Bootstrap Tester",
+		"GIT_AUTHOR_EMAIL=bootstrap-tester@buildkite.com",
+		"GIT_COMMITTER_NAME=Bootstrap Tester",
+		"GIT_COMMITTER_EMAIL=bootstrap-tester@buildkite.com",
+	)
+
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("git %v: %v\n%s", args, err, out)
+	}
+	return nil
+}
+
+func (b *shellRepoBackend) Init(dir string) error {
+	b.dir = dir
+	return b.git("init", ".")
+}
+
+func (b *shellRepoBackend) Add(path string) error {
+	return b.git("add", path)
+}
+
+func (b *shellRepoBackend) Commit(format string, args ...interface{}) error {
+	return b.git("commit", "-m", fmt.Sprintf(format, args...))
+}
+
+func (b *shellRepoBackend) CreateBranch(name string) error {
+	return b.git("branch", name)
+}
+
+func (b *shellRepoBackend) Tag(name string) error {
+	return b.git("tag", name)
+}
+
+func (b *shellRepoBackend) SetRemote(url string) error {
+	return b.git("remote", "add", "origin", url)
+}
+
+func (b *shellRepoBackend) AddSubmodule(path, url string) error {
+	return b.git("submodule", "add", url, path)
+}
@@ -0,0 +1,59 @@
+package integration
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestAddSubmoduleInterchangeableAcrossBackends proves that shellRepoBackend
+// and goGitRepoBackend satisfy the same AddSubmodule contract - clone url
+// into path and register it - so WithRepoBackend callers can swap one in
+// for the other without changing behaviour.
+func TestAddSubmoduleInterchangeableAcrossBackends(t *testing.T) {
+	backends := map[string]func(dir string) RepoBackend{
+		"shell": newShellRepoBackend,
+		"gogit": newGoGitRepoBackend,
+	}
+
+	for name, newBackend := range backends {
+		newBackend := newBackend
+		t.Run(name, func(t *testing.T) {
+			sub, err := createTestGitRespository()
+			if err != nil {
+				t.Fatalf("createTestGitRespository: %v", err)
+			}
+			defer sub.Close()
+
+			dir, err := ioutil.TempDir("", "bootstrap-git")
+			if err != nil {
+				t.Fatalf("TempDir: %v", err)
+			}
+			defer os.RemoveAll(dir)
+
+			repo, err := newTestGitRepository(dir, newBackend(dir))
+			if err != nil {
+				t.Fatalf("newTestGitRepository: %v", err)
+			}
+			defer repo.Close()
+
+			if err := repo.AddSubmodule("vendor/sub", sub.Path); err != nil {
+				t.Fatalf("AddSubmodule: %v", err)
+			}
+
+			gitmodules, err := ioutil.ReadFile(filepath.Join(dir, ".gitmodules"))
+			if err != nil {
+				t.Fatalf("reading .gitmodules: %v", err)
+			}
+			if !strings.Contains(string(gitmodules), "vendor/sub") {
+				t.Errorf(".gitmodules = %q, want an entry for vendor/sub", gitmodules)
+			}
+
+			if _, err := os.Stat(filepath.Join(dir, "vendor/sub", "test.txt")); err != nil {
+				t.Errorf("expected submodule to be cloned into vendor/sub: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,18 @@
+package integration
+
+// RepoBackend creates and manipulates the git repository fixture behind a
+// BootstrapTester. The default implementation shells out to a system git
+// binary; WithRepoBackend lets a test substitute another implementation,
+// such as the go-git-backed one in git_repository_gogit.go, when it needs
+// fixture shapes (submodules, signed commits, packed refs, shallow clones)
+// that are awkward to script with shell git, or when a system git binary
+// isn't reliably available.
+type RepoBackend interface {
+	Init(dir string) error
+	Add(path string) error
+	Commit(format string, args ...interface{}) error
+	CreateBranch(name string) error
+	Tag(name string) error
+	SetRemote(url string) error
+	AddSubmodule(path, url string) error
+}
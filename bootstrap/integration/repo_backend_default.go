@@ -0,0 +1,10 @@
+// +build !windows
+
+package integration
+
+// newDefaultRepoBackend returns the RepoBackend used when a test doesn't
+// call WithRepoBackend. Everywhere but Windows, that's a system git binary,
+// since it's reliably present and fastest to shell out to.
+func newDefaultRepoBackend(dir string) RepoBackend {
+	return newShellRepoBackend(dir)
+}
@@ -0,0 +1,11 @@
+// +build windows
+
+package integration
+
+// newDefaultRepoBackend returns the RepoBackend used when a test doesn't
+// call WithRepoBackend. On Windows CI, system git availability and version
+// are inconsistent enough that the go-git-backed implementation is the
+// more deterministic default.
+func newDefaultRepoBackend(dir string) RepoBackend {
+	return newGoGitRepoBackend(dir)
+}
@@ -0,0 +1,146 @@
+package bootstrap
+
+import (
+	"math"
+	"regexp"
+)
+
+// Well-known secret shapes that RegisterMatcher callers commonly want to
+// redact, even though the actual value was never passed to NewRedactor as a
+// literal needle.
+var (
+	AWSAccessKeyPattern = regexp.MustCompile(`AKIA[0-9A-Z]{16}`)
+	GitHubTokenPattern  = regexp.MustCompile(`gh[pousr]_[A-Za-z0-9]{36}`)
+	JWTPattern          = regexp.MustCompile(`eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+`)
+	PEMBlockPattern     = regexp.MustCompile(`(?s)-----BEGIN [A-Z ]+-----.*?-----END [A-Z ]+-----`)
+	BasicAuthURLPattern = regexp.MustCompile(`[a-zA-Z][a-zA-Z0-9+.-]*://[^\s:/@]+:[^\s:/@]+@`)
+)
+
+// RegexMatcher is a Matcher backed by a compiled regular expression. Name
+// identifies it in Stats()/OnRedact - without one, every RegexMatcher would
+// otherwise be indistinguishable from any other in aggregate counts.
+type RegexMatcher struct {
+	Name string
+
+	re *regexp.Regexp
+}
+
+// NewRegexMatcher compiles pattern into a Matcher identified as name.
+func NewRegexMatcher(name, pattern string) (*RegexMatcher, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	return &RegexMatcher{Name: name, re: re}, nil
+}
+
+func (m *RegexMatcher) Match(buf []byte) []Range {
+	locs := m.re.FindAllIndex(buf, -1)
+	if locs == nil {
+		return nil
+	}
+	ranges := make([]Range, len(locs))
+	for i, loc := range locs {
+		ranges[i] = Range{Start: loc[0], End: loc[1]}
+	}
+	return ranges
+}
+
+// MatcherID reports Name, so Stats()/OnRedact can tell which pattern fired.
+func (m *RegexMatcher) MatcherID() string {
+	return m.Name
+}
+
+// KnownSecretMatchers returns a Matcher for each well-known secret shape that
+// Redactor recognises out of the box (AWS access keys, GitHub tokens, JWTs,
+// PEM blocks and HTTP Basic Auth URLs), each identified by its own name in
+// Stats()/OnRedact.
+func KnownSecretMatchers() []Matcher {
+	named := []struct {
+		name string
+		re   *regexp.Regexp
+	}{
+		{"aws_access_key", AWSAccessKeyPattern},
+		{"github_token", GitHubTokenPattern},
+		{"jwt", JWTPattern},
+		{"pem_block", PEMBlockPattern},
+		{"basic_auth_url", BasicAuthURLPattern},
+	}
+	matchers := make([]Matcher, len(named))
+	for i, n := range named {
+		matchers[i] = &RegexMatcher{Name: n.name, re: n.re}
+	}
+	return matchers
+}
+
+// EntropyMatcher flags runs of token characters (alphanumerics plus the
+// handful of punctuation characters that show up in base64/hex tokens) whose
+// Shannon entropy is at or above MinBits per character, for catching
+// high-entropy secrets that don't match any known literal shape.
+type EntropyMatcher struct {
+	MinBits float64
+	MinLen  int
+}
+
+// NewEntropyMatcher returns an EntropyMatcher that flags token runs of at
+// least minLen characters with at least minBits of entropy per character.
+func NewEntropyMatcher(minBits float64, minLen int) *EntropyMatcher {
+	return &EntropyMatcher{MinBits: minBits, MinLen: minLen}
+}
+
+func (m *EntropyMatcher) Match(buf []byte) []Range {
+	var ranges []Range
+
+	start := -1
+	for i := 0; i <= len(buf); i++ {
+		if i < len(buf) && isTokenByte(buf[i]) {
+			if start == -1 {
+				start = i
+			}
+			continue
+		}
+		if start != -1 {
+			if i-start >= m.MinLen && shannonEntropy(buf[start:i]) >= m.MinBits {
+				ranges = append(ranges, Range{Start: start, End: i})
+			}
+			start = -1
+		}
+	}
+
+	return ranges
+}
+
+// isTokenByte reports whether b is a character commonly found in base64,
+// hex or URL-safe tokens.
+func isTokenByte(b byte) bool {
+	switch {
+	case b >= 'a' && b <= 'z', b >= 'A' && b <= 'Z', b >= '0' && b <= '9':
+		return true
+	case b == '+' || b == '/' || b == '=' || b == '_' || b == '-' || b == '.':
+		return true
+	}
+	return false
+}
+
+// shannonEntropy returns the Shannon entropy of data, in bits per character.
+func shannonEntropy(data []byte) float64 {
+	if len(data) == 0 {
+		return 0
+	}
+
+	var counts [256]int
+	for _, b := range data {
+		counts[b]++
+	}
+
+	n := float64(len(data))
+	var entropy float64
+	for _, c := range counts {
+		if c == 0 {
+			continue
+		}
+		p := float64(c) / n
+		entropy -= p * math.Log2(p)
+	}
+	return entropy
+}
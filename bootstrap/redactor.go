@@ -2,9 +2,33 @@ package bootstrap
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
 	"io"
+	"sort"
 )
 
+// Range marks a span of bytes, relative to the start of the buffer a Matcher
+// was given, that should be redacted.
+type Range struct {
+	Start, End int
+}
+
+// Matcher looks for secrets in buf that the fixed-needle Boyer-Moore pass
+// wouldn't catch, such as well-known secret shapes or high-entropy tokens,
+// and returns the ranges that matched.
+type Matcher interface {
+	Match(buf []byte) []Range
+}
+
+// defaultMatcherWindow is how many trailing bytes of already-matched output
+// are held back before being handed to redactor.output, so that a Matcher
+// pattern split across two Write calls is still caught. It's deliberately
+// much larger than the needle-based maxlen carry-over, since patterns like
+// a PEM block can span many lines.
+const defaultMatcherWindow = 8192
+
 type Redactor struct {
 	replacement []byte
 
@@ -26,10 +50,107 @@ type Redactor struct {
 	// sensitive values that cross Write boundaries
 	outbuf []byte
 
+	// Pattern-based matchers, run as a second pass after the Boyer-Moore
+	// needle search. Registered with RegisterMatcher.
+	matchers []Matcher
+
+	// How many bytes of Boyer-Moore output are held back for the matcher
+	// pass to see, and the bytes currently held back.
+	matcherWindow int
+	pending       []byte
+
+	// When set (via NewLineRedactor), Write buffers input into complete
+	// lines and matches against a decoded shadow copy of each line instead
+	// of scanning the raw byte stream. lineBuf holds the current partial
+	// line.
+	lineBuffered bool
+	lineBuf      []byte
+
+	// Cumulative byte offsets, used only to report an approximate position
+	// to OnRedact/Stats. streamPos tracks the raw byte stream (Write mode),
+	// matcherBase the post-Boyer-Moore stream the matcher pass sees, and
+	// lineStreamPos the raw byte stream in line-buffered mode.
+	streamPos     int64
+	matcherBase   int64
+	lineStreamPos int64
+
+	// OnRedact, if set, is called every time a match is replaced, with a
+	// hash of the matched bytes (never the bytes themselves) and its
+	// approximate offset in the input stream. Useful for structured
+	// metrics/logging about how much was scrubbed, without leaking secrets.
+	OnRedact func(needleHash string, offset int64)
+
+	// Aggregate redaction counts by matcher id, for Stats().
+	statsByMatcher map[string]int
+
 	// Wrapped Writer that we'll send redacted output to
 	output io.Writer
 }
 
+// Stats is a point-in-time snapshot of how many redactions a Redactor has
+// made, broken down by the matcher responsible. Fixed-needle matches via
+// the Boyer-Moore pass are counted under "needle".
+type Stats struct {
+	Total     int
+	ByMatcher map[string]int
+}
+
+// Stats returns a snapshot of the redaction counts made so far.
+func (redactor *Redactor) Stats() Stats {
+	byMatcher := make(map[string]int, len(redactor.statsByMatcher))
+	total := 0
+	for id, n := range redactor.statsByMatcher {
+		byMatcher[id] = n
+		total += n
+	}
+	return Stats{Total: total, ByMatcher: byMatcher}
+}
+
+// Reset clears all per-stream state - buffered bytes, offsets and redaction
+// counts - so the Redactor can be reused for a new job. Configured needles,
+// matchers and the OnRedact callback are left in place.
+func (redactor *Redactor) Reset() {
+	redactor.outbuf = redactor.outbuf[:0]
+	redactor.pending = redactor.pending[:0]
+	redactor.lineBuf = redactor.lineBuf[:0]
+	redactor.offset = redactor.minlen - 1
+	redactor.streamPos = 0
+	redactor.matcherBase = 0
+	redactor.lineStreamPos = 0
+	redactor.statsByMatcher = nil
+}
+
+// recordRedaction updates Stats and, if set, calls OnRedact for a single
+// redacted match.
+func (redactor *Redactor) recordRedaction(matcherID string, matched []byte, offset int64) {
+	if redactor.statsByMatcher == nil {
+		redactor.statsByMatcher = map[string]int{}
+	}
+	redactor.statsByMatcher[matcherID]++
+
+	if redactor.OnRedact != nil {
+		redactor.OnRedact(hashNeedle(matched), offset)
+	}
+}
+
+// hashNeedle hashes matched bytes so OnRedact/Stats can report on what was
+// found without ever surfacing the secret itself.
+func hashNeedle(matched []byte) string {
+	sum := sha256.Sum256(matched)
+	return hex.EncodeToString(sum[:])
+}
+
+// matcherID identifies which Matcher reported a Range, for Stats(). Matchers
+// that can tell themselves apart (e.g. several RegexMatchers for different
+// patterns) should implement MatcherID; otherwise matchers of the same Go
+// type would all collapse into one Stats() bucket.
+func matcherID(m Matcher) string {
+	if im, ok := m.(interface{ MatcherID() string }); ok {
+		return im.MatcherID()
+	}
+	return fmt.Sprintf("%T", m)
+}
+
 // Construct a new Redactor, and pre-compile the Boyer-Moore skip table
 func NewRedactor(output io.Writer, replacement string, needles []string) *Redactor {
 	minNeedleLen := 0
@@ -63,6 +184,8 @@ func NewRedactor(output io.Writer, replacement string, needles []string) *Redact
 		minlen: minNeedleLen,
 		maxlen: maxNeedleLen,
 		offset: minNeedleLen - 1,
+
+		matcherWindow: defaultMatcherWindow,
 	}
 
 	// For bytes that don't appear in any of the substrings we're searching
@@ -92,10 +215,113 @@ func NewRedactor(output io.Writer, replacement string, needles []string) *Redact
 	return redactor
 }
 
+// RegisterMatcher adds a pattern-based Matcher that runs over stream chunks
+// after the fixed-needle Boyer-Moore pass. The Boyer-Moore path remains the
+// fast path for known literal needles; matchers are for secret shapes (or
+// entropy) that can't be expressed as a literal string.
+func (redactor *Redactor) RegisterMatcher(m Matcher) {
+	redactor.matchers = append(redactor.matchers, m)
+}
+
+// emit hands Boyer-Moore-redacted output to the matcher pass. It holds back
+// matcherWindow bytes so a pattern match straddling a Write boundary is
+// still found before those bytes are sent on to output.
+func (redactor *Redactor) emit(chunk []byte) error {
+	if len(redactor.matchers) == 0 {
+		_, err := redactor.output.Write(chunk)
+		return err
+	}
+
+	redactor.pending = append(redactor.pending, chunk...)
+	if len(redactor.pending) <= redactor.matcherWindow {
+		return nil
+	}
+
+	flushTo := len(redactor.pending) - redactor.matcherWindow
+	_, err := redactor.output.Write(redactor.scanAndRedact(redactor.pending[:flushTo], redactor.matcherBase))
+	redactor.matcherBase += int64(flushTo)
+	redactor.pending = append(redactor.pending[:0], redactor.pending[flushTo:]...)
+	return err
+}
+
+// scanAndRedact runs every registered Matcher over buf, reconciles any
+// overlapping ranges they report, and returns a copy of buf with those
+// ranges replaced. base is buf's offset within the stream the matcher is
+// watching, used only to report a redaction's approximate position.
+func (redactor *Redactor) scanAndRedact(buf []byte, base int64) []byte {
+	var ranges []Range
+	for _, m := range redactor.matchers {
+		id := matcherID(m)
+		for _, r := range m.Match(buf) {
+			ranges = append(ranges, r)
+
+			end := r.End
+			if end > len(buf) {
+				end = len(buf)
+			}
+			redactor.recordRedaction(id, buf[r.Start:end], base+int64(r.Start))
+		}
+	}
+	if len(ranges) == 0 {
+		return buf
+	}
+	ranges = reconcileRanges(ranges)
+
+	out := make([]byte, 0, len(buf))
+	last := 0
+	for _, r := range ranges {
+		if r.Start > len(buf) {
+			break
+		}
+		end := r.End
+		if end > len(buf) {
+			end = len(buf)
+		}
+		out = append(out, buf[last:r.Start]...)
+		out = append(out, redactor.replacement...)
+		last = end
+	}
+	out = append(out, buf[last:]...)
+	return out
+}
+
+// reconcileRanges sorts ranges and merges any that overlap or touch, so a
+// byte span flagged by more than one matcher is only redacted once.
+func reconcileRanges(ranges []Range) []Range {
+	if len(ranges) < 2 {
+		return ranges
+	}
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i].Start < ranges[j].Start })
+
+	merged := ranges[:1]
+	for _, r := range ranges[1:] {
+		last := &merged[len(merged)-1]
+		if r.Start <= last.End {
+			if r.End > last.End {
+				last.End = r.End
+			}
+			continue
+		}
+		merged = append(merged, r)
+	}
+	return merged
+}
+
 func (redactor *Redactor) Write(input []byte) (int, error) {
+	if redactor.lineBuffered {
+		return redactor.writeLine(input)
+	}
+
 	// Current iterator index, which may be a safe offset from 0
 	cursor := redactor.offset
 
+	// With no needles configured (matcher-only mode), offset is -1 and
+	// there's nothing for the Boyer-Moore loop below to do - clamp so it
+	// doesn't index input at a negative position.
+	if redactor.minlen == 0 {
+		cursor = 0
+	}
+
 	// Current index which is guaranteed to be completely redacted
 	// May lag behind cursor by up to the length of the longest search string
 	doneTo := 0
@@ -161,6 +387,7 @@ func (redactor *Redactor) Write(input []byte) (int, error) {
 				}
 				// Then, write a fixed string into the output, and move doneTo past the redaction
 				redactor.outbuf = append(redactor.outbuf, redactor.replacement...)
+				redactor.recordRedaction("needle", candidate, redactor.streamPos+int64(startSubstr))
 				doneTo = cursor
 
 				// The next end-of-string will be at least this far away so
@@ -188,8 +415,8 @@ func (redactor *Redactor) Write(input []byte) (int, error) {
 
 	var err error
 	if doneTo > 0 {
-		// Push the output buffer down
-		_, err = redactor.output.Write(redactor.outbuf)
+		// Push the output buffer down, via the matcher pass if one's configured
+		err = redactor.emit(redactor.outbuf)
 
 		// There will probably be a segment at the end of the input which may be a
 		// partial match crossing the Write boundary. This is retained in the
@@ -206,13 +433,28 @@ func (redactor *Redactor) Write(input []byte) (int, error) {
 	// We can offset the next Write processing by how far cursor is ahead of
 	// the end of this input segment
 	redactor.offset = cursor - len(input)
+	redactor.streamPos += int64(len(input))
 
 	return len(input), err
 }
 
 // Flush should be called after the final Write. This will Write() anything
 // retained in case of a partial match and reset the output buffer.
-func (redactor Redactor) Sync() error {
+func (redactor *Redactor) Sync() error {
+	if redactor.lineBuffered && len(redactor.lineBuf) > 0 {
+		if _, err := redactor.output.Write(redactor.redactLine(redactor.lineBuf, redactor.lineStreamPos)); err != nil {
+			return err
+		}
+		redactor.lineStreamPos += int64(len(redactor.lineBuf))
+		redactor.lineBuf = redactor.lineBuf[:0]
+	}
+	if len(redactor.matchers) > 0 && len(redactor.pending) > 0 {
+		if _, err := redactor.output.Write(redactor.scanAndRedact(redactor.pending, redactor.matcherBase)); err != nil {
+			return err
+		}
+		redactor.matcherBase += int64(len(redactor.pending))
+		redactor.pending = redactor.pending[:0]
+	}
 	_, err := redactor.output.Write(redactor.outbuf)
 	redactor.outbuf = redactor.outbuf[:0]
 	return err
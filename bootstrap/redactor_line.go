@@ -0,0 +1,169 @@
+package bootstrap
+
+import (
+	"bytes"
+	"io"
+	"regexp"
+)
+
+// ansiEscapePattern matches ANSI CSI sequences, most commonly the SGR colour
+// codes CI tools use to colourise output.
+var ansiEscapePattern = regexp.MustCompile(`\x1b\[[0-9;]*[a-zA-Z]`)
+
+// timestampPrefixPattern matches the buildkite timestamp group marker that's
+// inserted at the start of each line when --timestamp-lines is enabled.
+var timestampPrefixPattern = regexp.MustCompile(`^_bk;t=\d+\x07?`)
+
+// NewLineRedactor constructs a Redactor that buffers input into complete
+// lines before matching, rather than scanning the raw byte stream. ANSI
+// escape sequences and buildkite timestamp markers are stripped into a
+// shadow copy of each line for matching purposes only, so a secret that's
+// been split up by interleaved escape codes (e.g. `sec\x1b[0mret`) is still
+// found. The original bytes - escape codes included - are emitted unchanged
+// outside of the redacted range, so colouring and alignment survive.
+func NewLineRedactor(output io.Writer, replacement string, needles []string) *Redactor {
+	redactor := NewRedactor(output, replacement, needles)
+	redactor.lineBuffered = true
+	return redactor
+}
+
+// writeLine buffers input until one or more complete lines are available,
+// then redacts and emits each in turn. A line ends at \n, or at \r - the
+// \r case handles progress bars/spinners that overwrite the current line
+// with \r instead of starting a new one with \n, so that output doesn't
+// stall in lineBuf until a real newline eventually arrives.
+func (redactor *Redactor) writeLine(input []byte) (int, error) {
+	redactor.lineBuf = append(redactor.lineBuf, input...)
+
+	for {
+		idx := bytes.IndexAny(redactor.lineBuf, "\r\n")
+		if idx == -1 {
+			break
+		}
+
+		line := redactor.lineBuf[:idx+1]
+		if _, err := redactor.output.Write(redactor.redactLine(line, redactor.lineStreamPos)); err != nil {
+			return len(input), err
+		}
+		redactor.lineStreamPos += int64(len(line))
+		redactor.lineBuf = append([]byte(nil), redactor.lineBuf[idx+1:]...)
+	}
+
+	return len(input), nil
+}
+
+// redactLine finds needle and matcher hits in line, decoding ANSI/timestamp
+// escapes into a shadow buffer first so that matching isn't thrown off by
+// bytes a human wouldn't consider part of the visible line, then applies
+// those redactions to the original bytes. base is line's offset within the
+// raw input stream, used only to report a redaction's approximate position.
+func (redactor *Redactor) redactLine(line []byte, base int64) []byte {
+	shadow, origIndex := stripEscapes(line)
+
+	var ranges []Range
+	for _, r := range redactor.findNeedleRanges(shadow) {
+		ranges = append(ranges, r)
+		redactor.recordRedaction("needle", shadow[r.Start:r.End], base+int64(r.Start))
+	}
+	for _, m := range redactor.matchers {
+		id := matcherID(m)
+		for _, r := range m.Match(shadow) {
+			ranges = append(ranges, r)
+			redactor.recordRedaction(id, shadow[r.Start:r.End], base+int64(r.Start))
+		}
+	}
+	if len(ranges) == 0 {
+		return line
+	}
+	ranges = reconcileRanges(ranges)
+
+	out := make([]byte, 0, len(line))
+	last := 0
+	for _, r := range ranges {
+		if r.Start >= len(origIndex) {
+			continue
+		}
+		origStart := origIndex[r.Start]
+
+		// origEnd is the position right after the last matched shadow byte,
+		// not the position of the next shadow byte - otherwise any escape
+		// bytes between the two (e.g. an SGR reset following a redacted
+		// secret) would be swallowed by the replacement instead of preserved.
+		origEnd := len(line)
+		if r.End-1 < len(origIndex) {
+			origEnd = origIndex[r.End-1] + 1
+		}
+		out = append(out, line[last:origStart]...)
+		out = append(out, redactor.replacement...)
+		last = origEnd
+	}
+	out = append(out, line[last:]...)
+	return out
+}
+
+// findNeedleRanges scans buf for any configured needle using the
+// Boyer-Moore skip table, returning the byte ranges that matched. Unlike
+// Write, buf is a whole line available up front, so there's no need to
+// track partial matches across a boundary.
+func (redactor *Redactor) findNeedleRanges(buf []byte) []Range {
+	if redactor.minlen == 0 {
+		return nil
+	}
+
+	var ranges []Range
+	cursor := redactor.minlen - 1
+	for cursor < len(buf) {
+		ch := buf[cursor]
+		skip := redactor.table[ch].skip
+		if skip != 0 {
+			cursor += skip
+			continue
+		}
+
+		cursor++
+		for _, needle := range redactor.table[ch].needles {
+			start := cursor - len(needle)
+			if start < 0 {
+				continue
+			}
+			if bytes.Equal(needle, buf[start:cursor]) {
+				ranges = append(ranges, Range{Start: start, End: cursor})
+				cursor += redactor.minlen - 1
+				break
+			}
+		}
+	}
+	return ranges
+}
+
+// stripEscapes removes ANSI CSI sequences and buildkite timestamp markers
+// from line, returning the visible ("shadow") bytes used for matching, plus
+// a mapping from each shadow byte's index back to its offset in line.
+func stripEscapes(line []byte) (shadow []byte, origIndex []int) {
+	shadow = make([]byte, 0, len(line))
+	origIndex = make([]int, 0, len(line))
+
+	i := 0
+	for i < len(line) {
+		if end := escapeEndAt(line, i); end > i {
+			i = end
+			continue
+		}
+		shadow = append(shadow, line[i])
+		origIndex = append(origIndex, i)
+		i++
+	}
+	return shadow, origIndex
+}
+
+// escapeEndAt returns the index immediately after an ANSI escape or
+// timestamp marker starting at i, or i itself if there isn't one there.
+func escapeEndAt(line []byte, i int) int {
+	if loc := ansiEscapePattern.FindIndex(line[i:]); loc != nil && loc[0] == 0 {
+		return i + loc[1]
+	}
+	if loc := timestampPrefixPattern.FindIndex(line[i:]); loc != nil && loc[0] == 0 {
+		return i + loc[1]
+	}
+	return i
+}
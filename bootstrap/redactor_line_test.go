@@ -0,0 +1,67 @@
+package bootstrap
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestLineRedactorPreservesEscapesAroundMatch(t *testing.T) {
+	var out bytes.Buffer
+	redactor := NewLineRedactor(&out, "[REDACTED]", []string{"secret"})
+
+	if _, err := redactor.Write([]byte("xxsecret\x1b[0myy\n")); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if err := redactor.Sync(); err != nil {
+		t.Fatalf("Sync: %v", err)
+	}
+
+	if got, want := out.String(), "xx[REDACTED]\x1b[0myy\n"; got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+}
+
+func TestLineRedactorPreservesTrailingResetCode(t *testing.T) {
+	var out bytes.Buffer
+	redactor := NewLineRedactor(&out, "[REDACTED]", []string{"secret"})
+
+	if _, err := redactor.Write([]byte("secret\x1b[0m\n")); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if err := redactor.Sync(); err != nil {
+		t.Fatalf("Sync: %v", err)
+	}
+
+	if got, want := out.String(), "[REDACTED]\x1b[0m\n"; got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+}
+
+func TestLineRedactorFlushesOnCarriageReturn(t *testing.T) {
+	var out bytes.Buffer
+	redactor := NewLineRedactor(&out, "[REDACTED]", []string{"secret"})
+
+	if _, err := redactor.Write([]byte("progress secret\r")); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+
+	if got, want := out.String(), "progress [REDACTED]\r"; got != want {
+		t.Errorf("output = %q, want %q - \\r should flush the line without waiting for \\n or Sync", got, want)
+	}
+}
+
+func TestLineRedactorMatchSplitAcrossANSICode(t *testing.T) {
+	var out bytes.Buffer
+	redactor := NewLineRedactor(&out, "[REDACTED]", []string{"secret"})
+
+	if _, err := redactor.Write([]byte("sec\x1b[0mret\n")); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if err := redactor.Sync(); err != nil {
+		t.Fatalf("Sync: %v", err)
+	}
+
+	if bytes.Contains(out.Bytes(), []byte("secret")) {
+		t.Errorf("output still contains the secret: %q", out.String())
+	}
+}
@@ -0,0 +1,113 @@
+package bootstrap
+
+import (
+	"bytes"
+	"testing"
+)
+
+// TestRedactorSyncDoesNotReemitTailOnSubsequentWrite guards against a past
+// bug where Sync used a value receiver, so resetting outbuf after a flush
+// never reached the real Redactor - the retained tail from the first Sync
+// would then be written out again on the second Write/Sync cycle.
+func TestRedactorSyncDoesNotReemitTailOnSubsequentWrite(t *testing.T) {
+	var out bytes.Buffer
+	redactor := NewRedactor(&out, "[REDACTED]", []string{"secret"})
+
+	if _, err := redactor.Write([]byte("one secret\n")); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if err := redactor.Sync(); err != nil {
+		t.Fatalf("Sync: %v", err)
+	}
+
+	if _, err := redactor.Write([]byte("two secret\n")); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if err := redactor.Sync(); err != nil {
+		t.Fatalf("Sync: %v", err)
+	}
+
+	got := out.String()
+	want := "one [REDACTED]\ntwo [REDACTED]\n"
+	if got != want {
+		t.Errorf("output = %q, want %q (a stale tail being re-emitted would duplicate content)", got, want)
+	}
+}
+
+// TestRedactorOnRedactReportsEachMatch checks that OnRedact fires once per
+// redaction with a hash rather than the secret itself.
+func TestRedactorOnRedactReportsEachMatch(t *testing.T) {
+	var out bytes.Buffer
+	redactor := NewRedactor(&out, "[REDACTED]", []string{"secret"})
+
+	var hashes []string
+	var offsets []int64
+	redactor.OnRedact = func(needleHash string, offset int64) {
+		hashes = append(hashes, needleHash)
+		offsets = append(offsets, offset)
+	}
+
+	if _, err := redactor.Write([]byte("a secret, then another secret\n")); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if err := redactor.Sync(); err != nil {
+		t.Fatalf("Sync: %v", err)
+	}
+
+	if len(hashes) != 2 {
+		t.Fatalf("OnRedact called %d times, want 2", len(hashes))
+	}
+	if hashes[0] != hashes[1] {
+		t.Errorf("hash of two identical secrets differed: %q vs %q", hashes[0], hashes[1])
+	}
+	for _, h := range hashes {
+		if bytes.Contains([]byte(h), []byte("secret")) {
+			t.Errorf("OnRedact hash %q leaks the original secret", h)
+		}
+	}
+	if offsets[0] >= offsets[1] {
+		t.Errorf("offsets = %v, want strictly increasing", offsets)
+	}
+}
+
+// TestRedactorResetClearsStreamStateButKeepsConfig checks that Reset wipes
+// per-stream state (stats, buffered tail) while leaving needles/matchers/
+// OnRedact usable for a fresh stream.
+func TestRedactorResetClearsStreamStateButKeepsConfig(t *testing.T) {
+	var out bytes.Buffer
+	redactor := NewRedactor(&out, "[REDACTED]", []string{"secret"})
+
+	redactCalls := 0
+	redactor.OnRedact = func(string, int64) { redactCalls++ }
+
+	if _, err := redactor.Write([]byte("a secret\n")); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if err := redactor.Sync(); err != nil {
+		t.Fatalf("Sync: %v", err)
+	}
+	if redactor.Stats().Total != 1 {
+		t.Fatalf("Stats().Total = %d, want 1 before Reset", redactor.Stats().Total)
+	}
+
+	redactor.Reset()
+	out.Reset()
+
+	if stats := redactor.Stats(); stats.Total != 0 {
+		t.Errorf("Stats().Total = %d, want 0 after Reset", stats.Total)
+	}
+
+	if _, err := redactor.Write([]byte("another secret\n")); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if err := redactor.Sync(); err != nil {
+		t.Fatalf("Sync: %v", err)
+	}
+
+	if got, want := out.String(), "another [REDACTED]\n"; got != want {
+		t.Errorf("output = %q, want %q - needles should still be configured after Reset", got, want)
+	}
+	if redactCalls != 2 {
+		t.Errorf("OnRedact called %d times total, want 2 (1 before Reset, 1 after)", redactCalls)
+	}
+}